@@ -0,0 +1,391 @@
+// Copyright (c) 2022 Arthur Skowronek <0x5a17ed@tuta.io> and contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <https://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efidevicepath
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// canonicalTexter is implemented by device paths that know how to render
+// themselves in the UEFI 2.x canonical text representation, the form used
+// by efibootmgr -p, bootctl and tianocore's dmpstore.
+type canonicalTexter interface {
+	CanonicalText() string
+}
+
+// FormatCanonical renders p in the UEFI canonical text representation, e.g.
+// "MAC(001122334455,0x1)" or "IPv4(192.168.0.1:80,TCP,Static,192.168.0.100,255.255.255.0)".
+func FormatCanonical(p DevicePath) (string, error) {
+	ct, ok := p.(canonicalTexter)
+	if !ok {
+		return "", fmt.Errorf("efidevicepath: %T has no canonical text representation", p)
+	}
+	return ct.CanonicalText(), nil
+}
+
+func (p *MACAddressDevicePath) CanonicalText() string {
+	return fmt.Sprintf("MAC(%s,0x%x)", hex.EncodeToString(p.MAC[:6]), p.AddrType)
+}
+
+func (p *SATADevicePath) CanonicalText() string {
+	return fmt.Sprintf("Sata(0x%x,0x%x,0x%x)", p.HBAPortNumber, p.PortMulPortNumber, p.LUN)
+}
+
+func (p *IPv4DevicePath) CanonicalText() string {
+	return fmt.Sprintf("IPv4(%s:%d,%s,%s,%s,%s)",
+		p.LocalIP, p.LocalPort, ipProtocolText(p.Protocol), ipOriginText(p.Static),
+		p.GatewayAddr, p.SubnetAddr)
+}
+
+func (p *IPv6DevicePath) CanonicalText() string {
+	return fmt.Sprintf("IPv6(%s,%s,%s,%s,0x%x)",
+		net.JoinHostPort(p.LocalIP.String(), strconv.Itoa(int(p.LocalPort))),
+		ipProtocolText(p.Protocol), ipv6OriginText(p.IPAddressOrigin),
+		p.GatewayAddr, p.PrefixLength)
+}
+
+func (p *URIDevicePath) CanonicalText() string {
+	return fmt.Sprintf("Uri(%s)", string(p.URI))
+}
+
+func (p *VendorMessagingDevicePath) CanonicalText() string {
+	return fmt.Sprintf("VenMsg(%s,%s)", formatGUID(p.GUID), hex.EncodeToString(p.VendorData))
+}
+
+func ipProtocolText(protocol uint16) string {
+	switch protocol {
+	case 6:
+		return "TCP"
+	case 17:
+		return "UDP"
+	default:
+		return strconv.Itoa(int(protocol))
+	}
+}
+
+func ipOriginText(static bool) string {
+	if static {
+		return "Static"
+	}
+	return "DHCP"
+}
+
+func ipv6OriginText(origin byte) string {
+	switch origin {
+	case 1:
+		return "StatelessAutoConfigure"
+	case 2:
+		return "StatefulAutoConfigure"
+	default:
+		return "Static"
+	}
+}
+
+// ParseText parses a UEFI canonical text device path, e.g.
+// "MAC(001122334455,0x1)/IPv4(192.168.0.1:80,TCP,Static,192.168.0.100,255.255.255.0)",
+// into the typed device paths it describes.
+func ParseText(s string) (paths []DevicePath, err error) {
+	for _, node := range splitNodes(s) {
+		node = strings.TrimSpace(node)
+		if node == "" {
+			continue
+		}
+
+		p, err := parseTextNode(node)
+		if err != nil {
+			return nil, fmt.Errorf("efidevicepath: parse %q: %w", node, err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// splitNodes splits a canonical text device path on the "/" that separate
+// its nodes, ignoring any "/" nested inside a node's argument list (e.g. the
+// path portion of a Uri(...) node).
+func splitNodes(s string) []string {
+	var nodes []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '/':
+			if depth == 0 {
+				nodes = append(nodes, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(nodes, s[start:])
+}
+
+func parseTextNode(node string) (DevicePath, error) {
+	open := strings.IndexByte(node, '(')
+	if open < 0 || !strings.HasSuffix(node, ")") {
+		return nil, fmt.Errorf("malformed node %q", node)
+	}
+
+	name := node[:open]
+	args := strings.Split(node[open+1:len(node)-1], ",")
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+
+	switch name {
+	case "MAC":
+		return parseMACText(args)
+	case "Sata":
+		return parseSataText(args)
+	case "IPv4":
+		return parseIPv4Text(args)
+	case "IPv6":
+		return parseIPv6Text(args)
+	case "Uri":
+		return &URIDevicePath{
+			Head: Head{Type: 3, SubType: URIDeviceSubType},
+			URI:  []byte(strings.Join(args, ",")),
+		}, nil
+	case "VenMsg":
+		return parseVenMsgText(args)
+	default:
+		return nil, fmt.Errorf("unsupported node type %q", name)
+	}
+}
+
+func parseMACText(args []string) (DevicePath, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("MAC wants 2 arguments, got %d", len(args))
+	}
+
+	raw, err := hex.DecodeString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("MAC address %q: %w", args[0], err)
+	}
+
+	addrType, err := strconv.ParseUint(strings.TrimPrefix(args[1], "0x"), 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("MAC address type %q: %w", args[1], err)
+	}
+
+	p := &MACAddressDevicePath{Head: Head{Type: 3, SubType: MACAddressSubType}, AddrType: byte(addrType)}
+	copy(p.MAC[:], raw)
+	return p, nil
+}
+
+func parseSataText(args []string) (DevicePath, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("Sata wants 3 arguments, got %d", len(args))
+	}
+
+	hba, err := parseHexUint16(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("Sata HBA port %q: %w", args[0], err)
+	}
+	portMul, err := parseHexUint16(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("Sata port multiplier %q: %w", args[1], err)
+	}
+	lun, err := parseHexUint16(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("Sata LUN %q: %w", args[2], err)
+	}
+
+	return &SATADevicePath{
+		Head:              Head{Type: 3, SubType: SATADeviceSubType},
+		HBAPortNumber:     hba,
+		PortMulPortNumber: portMul,
+		LUN:               lun,
+	}, nil
+}
+
+func parseIPv4Text(args []string) (DevicePath, error) {
+	if len(args) != 5 {
+		return nil, fmt.Errorf("IPv4 wants 5 arguments, got %d", len(args))
+	}
+
+	localIP, localPort, err := splitHostPort(args[0])
+	if err != nil {
+		return nil, err
+	}
+	localIP = localIP.To4()
+
+	gateway := net.ParseIP(args[3]).To4()
+	subnet := net.ParseIP(args[4]).To4()
+	if localIP == nil || gateway == nil || subnet == nil {
+		return nil, fmt.Errorf("IPv4 address %q/gateway %q/subnet %q invalid", args[0], args[3], args[4])
+	}
+
+	return &IPv4DevicePath{
+		Head:        Head{Type: 3, SubType: IPv4DeviceSubType},
+		LocalIP:     localIP,
+		RemoteIP:    net.IPv4zero.To4(),
+		LocalPort:   localPort,
+		Protocol:    protocolFromText(args[1]),
+		Static:      args[2] == "Static",
+		GatewayAddr: gateway,
+		SubnetAddr:  subnet,
+	}, nil
+}
+
+func parseIPv6Text(args []string) (DevicePath, error) {
+	if len(args) != 5 {
+		return nil, fmt.Errorf("IPv6 wants 5 arguments, got %d", len(args))
+	}
+
+	localIP, localPort, err := splitHostPort(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	gateway := net.ParseIP(args[3])
+	if gateway == nil {
+		return nil, fmt.Errorf("IPv6 gateway %q invalid", args[3])
+	}
+
+	prefixLen, err := parseHexByte(args[4])
+	if err != nil {
+		return nil, fmt.Errorf("IPv6 prefix length %q: %w", args[4], err)
+	}
+
+	return &IPv6DevicePath{
+		Head:            Head{Type: 3, SubType: IPv6DeviceSubType},
+		LocalIP:         localIP,
+		RemoteIP:        net.IPv6unspecified,
+		LocalPort:       localPort,
+		Protocol:        protocolFromText(args[1]),
+		IPAddressOrigin: ipv6OriginFromText(args[2]),
+		PrefixLength:    prefixLen,
+		GatewayAddr:     gateway,
+	}, nil
+}
+
+func parseVenMsgText(args []string) (DevicePath, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("VenMsg wants 2 arguments, got %d", len(args))
+	}
+
+	guid, err := parseGUID(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("VenMsg GUID %q: %w", args[0], err)
+	}
+
+	data, err := hex.DecodeString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("VenMsg data %q: %w", args[1], err)
+	}
+
+	return &VendorMessagingDevicePath{
+		Head:       Head{Type: 3, SubType: VENDORDeviceSubType},
+		GUID:       guid,
+		VendorData: data,
+	}, nil
+}
+
+// parseGUID parses the "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx" textual GUID
+// form produced by formatGUID back into its little-endian wire encoding.
+func parseGUID(s string) (g [16]byte, err error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 5 {
+		return g, fmt.Errorf("malformed GUID %q", s)
+	}
+
+	d1, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return g, err
+	}
+	d2, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return g, err
+	}
+	d3, err := strconv.ParseUint(parts[2], 16, 16)
+	if err != nil {
+		return g, err
+	}
+	d4, err := hex.DecodeString(parts[3])
+	if err != nil || len(d4) != 2 {
+		return g, fmt.Errorf("malformed GUID clock-seq field %q", parts[3])
+	}
+	d5, err := hex.DecodeString(parts[4])
+	if err != nil || len(d5) != 6 {
+		return g, fmt.Errorf("malformed GUID node field %q", parts[4])
+	}
+
+	binary.LittleEndian.PutUint32(g[0:4], uint32(d1))
+	binary.LittleEndian.PutUint16(g[4:6], uint16(d2))
+	binary.LittleEndian.PutUint16(g[6:8], uint16(d3))
+	copy(g[8:10], d4)
+	copy(g[10:16], d5)
+	return g, nil
+}
+
+func splitHostPort(s string) (net.IP, uint16, error) {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return nil, 0, fmt.Errorf("host:port %q: %w", s, err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, 0, fmt.Errorf("address %q invalid", host)
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("port %q: %w", portStr, err)
+	}
+
+	return ip, uint16(port), nil
+}
+
+func protocolFromText(s string) uint16 {
+	switch s {
+	case "TCP":
+		return 6
+	case "UDP":
+		return 17
+	default:
+		v, _ := strconv.ParseUint(s, 10, 16)
+		return uint16(v)
+	}
+}
+
+func ipv6OriginFromText(s string) byte {
+	switch s {
+	case "StatelessAutoConfigure":
+		return 1
+	case "StatefulAutoConfigure":
+		return 2
+	default:
+		return 0
+	}
+}
+
+func parseHexUint16(s string) (uint16, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 16)
+	return uint16(v), err
+}
+
+func parseHexByte(s string) (byte, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 8)
+	return byte(v), err
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2022 Arthur Skowronek <0x5a17ed@tuta.io> and contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <https://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efidevicepath
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const (
+	endDevicePathType      = 0x7f
+	endEntireSubType       = 0xff
+	endEntireDevicePathLen = 4
+)
+
+// Marshal serializes paths back into the raw UEFI device path byte stream
+// that firmware and Boot#### variables expect, appending the End of
+// Hardware Device Path terminator required to close the list.
+func Marshal(paths []DevicePath) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, p := range paths {
+		if _, err := p.WriteTo(&buf); err != nil {
+			return nil, fmt.Errorf("marshal device path %d: %w", i, err)
+		}
+	}
+
+	buf.Write([]byte{endDevicePathType, endEntireSubType, endEntireDevicePathLen, 0})
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2022 Arthur Skowronek <0x5a17ed@tuta.io> and contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <https://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efidevicepath
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// roundTrip writes p with WriteTo, re-parses the resulting bytes through
+// ParseMessagingDevicePath, and returns what came back out.
+func roundTrip(t *testing.T, p DevicePath) DevicePath {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := p.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	raw := buf.Bytes()
+	h := Head{Type: raw[0], SubType: raw[1], Length: uint16(raw[2]) | uint16(raw[3])<<8}
+
+	got, err := ParseMessagingDevicePath(bytes.NewReader(raw[4:]), h)
+	if err != nil {
+		t.Fatalf("ParseMessagingDevicePath: %v", err)
+	}
+	return got
+}
+
+func TestWriteToRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		path DevicePath
+	}{
+		{"MAC", &MACAddressDevicePath{
+			Head:     Head{Type: 3, SubType: MACAddressSubType},
+			MAC:      [32]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+			AddrType: 1,
+		}},
+		{"SATA", &SATADevicePath{
+			Head:              Head{Type: 3, SubType: SATADeviceSubType},
+			HBAPortNumber:     1,
+			PortMulPortNumber: 0xffff,
+			LUN:               0,
+		}},
+		{"IPv4", &IPv4DevicePath{
+			Head:        Head{Type: 3, SubType: IPv4DeviceSubType},
+			LocalIP:     net.IPv4(192, 168, 0, 100).To4(),
+			RemoteIP:    net.IPv4(192, 168, 0, 1).To4(),
+			LocalPort:   80,
+			RemotePort:  8080,
+			Protocol:    6,
+			Static:      true,
+			GatewayAddr: net.IPv4(192, 168, 0, 1).To4(),
+			SubnetAddr:  net.IPv4(255, 255, 255, 0).To4(),
+		}},
+		{"IPv6", &IPv6DevicePath{
+			Head:            Head{Type: 3, SubType: IPv6DeviceSubType},
+			LocalIP:         net.ParseIP("2001:db8::1"),
+			RemoteIP:        net.ParseIP("2001:db8::2"),
+			LocalPort:       443,
+			RemotePort:      443,
+			Protocol:        6,
+			IPAddressOrigin: 1,
+			PrefixLength:    64,
+			GatewayAddr:     net.ParseIP("2001:db8::ff"),
+		}},
+		{"URI", &URIDevicePath{
+			Head: Head{Type: 3, SubType: URIDeviceSubType},
+			URI:  []byte("http://192.168.0.1/boot.efi"),
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := roundTrip(t, c.path); !reflect.DeepEqual(got, c.path) {
+				t.Errorf("round trip mismatch:\n got  %#v\n want %#v", got, c.path)
+			}
+		})
+	}
+}
+
+func TestMarshalAppendsEndOfHardwareTerminator(t *testing.T) {
+	paths := []DevicePath{
+		&MACAddressDevicePath{Head: Head{Type: 3, SubType: MACAddressSubType}, MAC: [32]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, AddrType: 1},
+	}
+
+	raw, err := Marshal(paths)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	term := raw[len(raw)-4:]
+	want := []byte{endDevicePathType, endEntireSubType, endEntireDevicePathLen, 0}
+	if !bytes.Equal(term, want) {
+		t.Errorf("terminator = % x, want % x", term, want)
+	}
+}
+
+// FuzzIPv4DevicePathRoundTrip asserts that any 23-byte IPv4 messaging device
+// path payload whose Static byte is 0 or 1 parses and re-serializes back to
+// the same bytes, i.e. WriteTo(ReadFrom(x)) == x.
+func FuzzIPv4DevicePathRoundTrip(f *testing.F) {
+	f.Add([]byte{
+		0xc0, 0xa8, 0x00, 0x64,
+		0xc0, 0xa8, 0x00, 0x01,
+		0x00, 0x50,
+		0x1f, 0x90,
+		0x06, 0x00,
+		0x01,
+		0xc0, 0xa8, 0x00, 0x01,
+		0xff, 0xff, 0xff, 0x00,
+	})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		if len(payload) != 23 {
+			t.Skip()
+		}
+		// Static (offset 14) is a UEFI BOOLEAN: only 0 and 1 are canonical
+		// wire values, and Go's bool round-trips exactly those two, not
+		// arbitrary non-zero bytes.
+		if payload[14] > 1 {
+			t.Skip()
+		}
+
+		p := &IPv4DevicePath{Head: Head{Type: 3, SubType: IPv4DeviceSubType, Length: 27}}
+		if _, err := p.ReadFrom(bytes.NewReader(payload)); err != nil {
+			t.Fatalf("ReadFrom: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if _, err := p.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo: %v", err)
+		}
+
+		if got := buf.Bytes()[4:]; !bytes.Equal(got, payload) {
+			t.Errorf("round trip mismatch:\n got  % x\n want % x", got, payload)
+		}
+	})
+}
@@ -15,29 +15,67 @@
 package efidevicepath
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 	"net"
+	"strings"
+	"unicode/utf16"
 
 	"github.com/blindson76/uefi/efi/efireader"
 )
 
 const (
-	URIDeviceSubType    = 10
-	MACAddressSubType   = 11
-	IPv4DeviceSubType   = 12
-	SATADeviceSubType   = 18
-	VENDORDeviceSubType = 24
+	USBDeviceSubType         = 5
+	URIDeviceSubType         = 10
+	MACAddressSubType        = 11
+	IPv4DeviceSubType        = 12
+	IPv6DeviceSubType        = 13
+	USBClassDeviceSubType    = 15
+	USBWWIDDeviceSubType     = 16
+	DeviceLogicalUnitSubType = 17
+	SATADeviceSubType        = 18
+	ISCSIDeviceSubType       = 19
+	VLANDeviceSubType        = 20
+	NVMeNamespaceSubType     = 23
+	VENDORDeviceSubType      = 24
+	BluetoothDeviceSubType   = 27
+	WiFiDeviceSubType        = 28
+	DNSDeviceSubType         = 31
 )
 
 func ParseMessagingDevicePath(r io.Reader, h Head) (p DevicePath, err error) {
 	switch h.SubType {
+	case USBDeviceSubType:
+		p = &USBDevicePath{Head: h}
 	case MACAddressSubType:
 		p = &MACAddressDevicePath{Head: h}
 	case IPv4DeviceSubType:
 		p = &IPv4DevicePath{Head: h}
+	case IPv6DeviceSubType:
+		p = &IPv6DevicePath{Head: h}
+	case USBClassDeviceSubType:
+		p = &USBClassDevicePath{Head: h}
+	case USBWWIDDeviceSubType:
+		p = &USBWWIDDevicePath{Head: h}
+	case DeviceLogicalUnitSubType:
+		p = &DeviceLogicalUnitDevicePath{Head: h}
 	case SATADeviceSubType:
 		p = &SATADevicePath{Head: h}
+	case ISCSIDeviceSubType:
+		p = &ISCSIDevicePath{Head: h}
+	case VLANDeviceSubType:
+		p = &VLANDevicePath{Head: h}
+	case NVMeNamespaceSubType:
+		p = &NVMeNamespaceDevicePath{Head: h}
+	case VENDORDeviceSubType:
+		p = &VendorMessagingDevicePath{Head: h}
+	case BluetoothDeviceSubType:
+		p = &BluetoothDevicePath{Head: h}
+	case WiFiDeviceSubType:
+		p = &WiFiDevicePath{Head: h}
+	case DNSDeviceSubType:
+		p = &DNSDevicePath{Head: h}
 	case URIDeviceSubType:
 		p = &URIDevicePath{Head: h}
 	default:
@@ -70,6 +108,14 @@ func (p *MACAddressDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
 	return
 }
 
+func (p *MACAddressDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = uint16(4 + len(p.MAC) + 1)
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.MAC, &p.AddrType)
+	return
+}
+
 type SATADevicePath struct {
 	Head
 	HBAPortNumber     uint16
@@ -94,6 +140,14 @@ func (p *SATADevicePath) ReadFrom(r io.Reader) (n int64, err error) {
 	return
 }
 
+func (p *SATADevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = 4 + 2 + 2 + 2
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.HBAPortNumber, &p.PortMulPortNumber, &p.LUN)
+	return
+}
+
 type IPv4DevicePath struct {
 	Head
 	LocalIP     net.IP
@@ -121,12 +175,74 @@ func (p *IPv4DevicePath) ReadFrom(r io.Reader) (n int64, err error) {
 	p.SubnetAddr = make(net.IP, 4)
 	p.GatewayAddr = make(net.IP, 4)
 
-	if err = fr.ReadFields(&p.LocalIP, &p.RemoteIP, &p.LocalPort, &p.RemotePort); err != nil {
+	if err = fr.ReadFields(
+		&p.LocalIP, &p.RemoteIP, &p.LocalPort, &p.RemotePort,
+		&p.Protocol, &p.Static, &p.GatewayAddr, &p.SubnetAddr,
+	); err != nil {
+		return
+	}
+	return
+}
+
+func (p *IPv4DevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = 4 + 4 + 4 + 2 + 2 + 2 + 1 + 4 + 4
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(
+		&p.Head.Type, &p.Head.SubType, &p.Head.Length,
+		&p.LocalIP, &p.RemoteIP, &p.LocalPort, &p.RemotePort,
+		&p.Protocol, &p.Static, &p.GatewayAddr, &p.SubnetAddr,
+	)
+	return
+}
+
+type IPv6DevicePath struct {
+	Head
+	LocalIP         net.IP
+	RemoteIP        net.IP
+	LocalPort       uint16
+	RemotePort      uint16
+	Protocol        uint16
+	IPAddressOrigin byte
+	PrefixLength    byte
+	GatewayAddr     net.IP
+}
+
+func (p *IPv6DevicePath) Text() string {
+	return fmt.Sprintf("IPv6 Local:%s Remote:%s", p.LocalIP.String(), p.RemoteIP.String())
+}
+
+func (p *IPv6DevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *IPv6DevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	fr := efireader.NewFieldReader(r, &n)
+	p.LocalIP = make(net.IP, 16)
+	p.RemoteIP = make(net.IP, 16)
+	p.GatewayAddr = make(net.IP, 16)
+
+	if err = fr.ReadFields(
+		&p.LocalIP, &p.RemoteIP, &p.LocalPort, &p.RemotePort,
+		&p.Protocol, &p.IPAddressOrigin, &p.PrefixLength, &p.GatewayAddr,
+	); err != nil {
 		return
 	}
 	return
 }
 
+func (p *IPv6DevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = 4 + 16 + 16 + 2 + 2 + 2 + 1 + 1 + 16
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(
+		&p.Head.Type, &p.Head.SubType, &p.Head.Length,
+		&p.LocalIP, &p.RemoteIP, &p.LocalPort, &p.RemotePort,
+		&p.Protocol, &p.IPAddressOrigin, &p.PrefixLength, &p.GatewayAddr,
+	)
+	return
+}
+
 type URIDevicePath struct {
 	Head
 	URI []byte
@@ -149,3 +265,443 @@ func (p *URIDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
 	}
 	return
 }
+
+func (p *URIDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = uint16(4 + len(p.URI))
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.URI)
+	return
+}
+
+// WriteTo serializes an UnrecognizedDevicePath back to its original raw
+// bytes, allowing device paths containing subtypes this package doesn't
+// understand yet to still round-trip through Marshal unscathed.
+func (p *UnrecognizedDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = uint16(4 + len(p.Data))
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.Data)
+	return
+}
+
+type USBDevicePath struct {
+	Head
+	ParentPortNumber byte
+	InterfaceNumber  byte
+}
+
+func (p *USBDevicePath) Text() string {
+	return fmt.Sprintf("USB Parent Port: %d Interface: %d", p.ParentPortNumber, p.InterfaceNumber)
+}
+
+func (p *USBDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *USBDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	fr := efireader.NewFieldReader(r, &n)
+
+	if err = fr.ReadFields(&p.ParentPortNumber, &p.InterfaceNumber); err != nil {
+		return
+	}
+	return
+}
+
+func (p *USBDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = 4 + 1 + 1
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.ParentPortNumber, &p.InterfaceNumber)
+	return
+}
+
+type USBClassDevicePath struct {
+	Head
+	VendorID       uint16
+	ProductID      uint16
+	DeviceClass    byte
+	DeviceSubClass byte
+	DeviceProtocol byte
+}
+
+func (p *USBClassDevicePath) Text() string {
+	return fmt.Sprintf("USB Class Vendor:0x%04x Product:0x%04x Class:%d SubClass:%d Protocol:%d",
+		p.VendorID, p.ProductID, p.DeviceClass, p.DeviceSubClass, p.DeviceProtocol)
+}
+
+func (p *USBClassDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *USBClassDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	fr := efireader.NewFieldReader(r, &n)
+
+	if err = fr.ReadFields(
+		&p.VendorID, &p.ProductID, &p.DeviceClass, &p.DeviceSubClass, &p.DeviceProtocol,
+	); err != nil {
+		return
+	}
+	return
+}
+
+func (p *USBClassDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = 4 + 2 + 2 + 1 + 1 + 1
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(
+		&p.Head.Type, &p.Head.SubType, &p.Head.Length,
+		&p.VendorID, &p.ProductID, &p.DeviceClass, &p.DeviceSubClass, &p.DeviceProtocol,
+	)
+	return
+}
+
+type USBWWIDDevicePath struct {
+	Head
+	InterfaceNumber uint16
+	VendorID        uint16
+	ProductID       uint16
+	SerialNumber    []uint16
+}
+
+func (p *USBWWIDDevicePath) Text() string {
+	return fmt.Sprintf("USB WWID Interface:%d Vendor:0x%04x Product:0x%04x Serial:%s",
+		p.InterfaceNumber, p.VendorID, p.ProductID, string(utf16.Decode(p.SerialNumber)))
+}
+
+func (p *USBWWIDDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *USBWWIDDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	if p.Length < 10 {
+		return 0, fmt.Errorf("efidevicepath: USB WWID node too short: Length=%d", p.Length)
+	}
+
+	fr := efireader.NewFieldReader(r, &n)
+	p.SerialNumber = make([]uint16, (int(p.Length)-10)/2)
+
+	if err = fr.ReadFields(&p.InterfaceNumber, &p.VendorID, &p.ProductID, &p.SerialNumber); err != nil {
+		return
+	}
+	return
+}
+
+func (p *USBWWIDDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = uint16(4 + 2 + 2 + 2 + len(p.SerialNumber)*2)
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(
+		&p.Head.Type, &p.Head.SubType, &p.Head.Length,
+		&p.InterfaceNumber, &p.VendorID, &p.ProductID, &p.SerialNumber,
+	)
+	return
+}
+
+type DeviceLogicalUnitDevicePath struct {
+	Head
+	LUN byte
+}
+
+func (p *DeviceLogicalUnitDevicePath) Text() string {
+	return fmt.Sprintf("LUN: %d", p.LUN)
+}
+
+func (p *DeviceLogicalUnitDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *DeviceLogicalUnitDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	fr := efireader.NewFieldReader(r, &n)
+
+	if err = fr.ReadFields(&p.LUN); err != nil {
+		return
+	}
+	return
+}
+
+func (p *DeviceLogicalUnitDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = 4 + 1
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.LUN)
+	return
+}
+
+type ISCSIDevicePath struct {
+	Head
+	Protocol             uint16
+	LoginOptions         uint16
+	LUN                  uint64
+	TargetPortalGroupTag uint16
+	TargetName           []byte
+}
+
+func (p *ISCSIDevicePath) Text() string {
+	return fmt.Sprintf("iSCSI Target:%s LUN:%d TPGT:%d Protocol:%d",
+		string(p.TargetName), p.LUN, p.TargetPortalGroupTag, p.Protocol)
+}
+
+func (p *ISCSIDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *ISCSIDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	if p.Length < 18 {
+		return 0, fmt.Errorf("efidevicepath: iSCSI node too short: Length=%d", p.Length)
+	}
+
+	fr := efireader.NewFieldReader(r, &n)
+	p.TargetName = make([]byte, int(p.Length)-18)
+
+	if err = fr.ReadFields(
+		&p.Protocol, &p.LoginOptions, &p.LUN, &p.TargetPortalGroupTag, &p.TargetName,
+	); err != nil {
+		return
+	}
+	return
+}
+
+func (p *ISCSIDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = uint16(4 + 2 + 2 + 8 + 2 + len(p.TargetName))
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(
+		&p.Head.Type, &p.Head.SubType, &p.Head.Length,
+		&p.Protocol, &p.LoginOptions, &p.LUN, &p.TargetPortalGroupTag, &p.TargetName,
+	)
+	return
+}
+
+type VLANDevicePath struct {
+	Head
+	VLANID uint16
+}
+
+func (p *VLANDevicePath) Text() string {
+	return fmt.Sprintf("VLAN ID: %d", p.VLANID)
+}
+
+func (p *VLANDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *VLANDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	fr := efireader.NewFieldReader(r, &n)
+
+	if err = fr.ReadFields(&p.VLANID); err != nil {
+		return
+	}
+	return
+}
+
+func (p *VLANDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = 4 + 2
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.VLANID)
+	return
+}
+
+type NVMeNamespaceDevicePath struct {
+	Head
+	NamespaceID uint32
+	IEEEEUI64   uint64
+}
+
+func (p *NVMeNamespaceDevicePath) Text() string {
+	return fmt.Sprintf("NVMe Namespace:%d EUI64:%016x", p.NamespaceID, p.IEEEEUI64)
+}
+
+func (p *NVMeNamespaceDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *NVMeNamespaceDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	fr := efireader.NewFieldReader(r, &n)
+
+	if err = fr.ReadFields(&p.NamespaceID, &p.IEEEEUI64); err != nil {
+		return
+	}
+	return
+}
+
+func (p *NVMeNamespaceDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = 4 + 4 + 8
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.NamespaceID, &p.IEEEEUI64)
+	return
+}
+
+type VendorMessagingDevicePath struct {
+	Head
+	GUID       [16]byte
+	VendorData []byte
+}
+
+func (p *VendorMessagingDevicePath) Text() string {
+	return fmt.Sprintf("Vendor GUID:%s Data:%x", formatGUID(p.GUID), p.VendorData)
+}
+
+func (p *VendorMessagingDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *VendorMessagingDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	if p.Length < 20 {
+		return 0, fmt.Errorf("efidevicepath: vendor-messaging node too short: Length=%d", p.Length)
+	}
+
+	fr := efireader.NewFieldReader(r, &n)
+	p.VendorData = make([]byte, int(p.Length)-20)
+
+	if err = fr.ReadFields(&p.GUID, &p.VendorData); err != nil {
+		return
+	}
+	return
+}
+
+func (p *VendorMessagingDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = uint16(4 + 16 + len(p.VendorData))
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.GUID, &p.VendorData)
+	return
+}
+
+func formatGUID(b [16]byte) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10],
+		b[10:16])
+}
+
+type BluetoothDevicePath struct {
+	Head
+	BDAddr [6]byte
+}
+
+func (p *BluetoothDevicePath) Text() string {
+	return fmt.Sprintf("Bluetooth BD_ADDR: %s", net.HardwareAddr(p.BDAddr[:]).String())
+}
+
+func (p *BluetoothDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *BluetoothDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	fr := efireader.NewFieldReader(r, &n)
+
+	if err = fr.ReadFields(&p.BDAddr); err != nil {
+		return
+	}
+	return
+}
+
+func (p *BluetoothDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = 4 + 6
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.BDAddr)
+	return
+}
+
+type WiFiDevicePath struct {
+	Head
+	SSID [32]byte
+}
+
+func (p *WiFiDevicePath) Text() string {
+	return fmt.Sprintf("Wi-Fi SSID: %s", strings.TrimRight(string(p.SSID[:]), "\x00"))
+}
+
+func (p *WiFiDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *WiFiDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	fr := efireader.NewFieldReader(r, &n)
+
+	if err = fr.ReadFields(&p.SSID); err != nil {
+		return
+	}
+	return
+}
+
+func (p *WiFiDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	p.Head.Length = 4 + 32
+
+	fw := efireader.NewFieldWriter(w, &n)
+	err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.SSID)
+	return
+}
+
+type DNSDevicePath struct {
+	Head
+	IsIPv6     byte
+	DNSServers []net.IP
+}
+
+func (p *DNSDevicePath) Text() string {
+	addrs := make([]string, len(p.DNSServers))
+	for i, ip := range p.DNSServers {
+		addrs[i] = ip.String()
+	}
+	return fmt.Sprintf("DNS Servers: %s", strings.Join(addrs, ","))
+}
+
+func (p *DNSDevicePath) GetHead() *Head {
+	return &p.Head
+}
+
+func (p *DNSDevicePath) ReadFrom(r io.Reader) (n int64, err error) {
+	if p.Length < 5 {
+		return 0, fmt.Errorf("efidevicepath: DNS node too short: Length=%d", p.Length)
+	}
+
+	fr := efireader.NewFieldReader(r, &n)
+
+	if err = fr.ReadFields(&p.IsIPv6); err != nil {
+		return
+	}
+
+	addrSize := 4
+	if p.IsIPv6 != 0 {
+		addrSize = 16
+	}
+
+	raw := make([]byte, int(p.Length)-5)
+	if err = fr.ReadFields(&raw); err != nil {
+		return
+	}
+
+	for off := 0; off+addrSize <= len(raw); off += addrSize {
+		ip := make(net.IP, addrSize)
+		copy(ip, raw[off:off+addrSize])
+		p.DNSServers = append(p.DNSServers, ip)
+	}
+	return
+}
+
+func (p *DNSDevicePath) WriteTo(w io.Writer) (n int64, err error) {
+	addrSize := 4
+	if p.IsIPv6 != 0 {
+		addrSize = 16
+	}
+	p.Head.Length = uint16(4 + 1 + len(p.DNSServers)*addrSize)
+
+	fw := efireader.NewFieldWriter(w, &n)
+	if err = fw.WriteFields(&p.Head.Type, &p.Head.SubType, &p.Head.Length, &p.IsIPv6); err != nil {
+		return
+	}
+
+	for i := range p.DNSServers {
+		if err = fw.WriteFields(&p.DNSServers[i]); err != nil {
+			return
+		}
+	}
+	return
+}
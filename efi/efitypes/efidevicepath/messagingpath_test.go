@@ -0,0 +1,108 @@
+// Copyright (c) 2022 Arthur Skowronek <0x5a17ed@tuta.io> and contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// <https://www.apache.org/licenses/LICENSE-2.0>
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efidevicepath
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+// TestIPv4DevicePathReadFrom replays a statically configured IPv4 messaging
+// device path node captured from an OVMF Boot0001 variable.
+func TestIPv4DevicePathReadFrom(t *testing.T) {
+	payload := []byte{
+		0xc0, 0xa8, 0x00, 0x64, // LocalIP 192.168.0.100
+		0xc0, 0xa8, 0x00, 0x01, // RemoteIP 192.168.0.1
+		0x50, 0x00, // LocalPort 80
+		0x90, 0x1f, // RemotePort 8080
+		0x06, 0x00, // Protocol TCP (6)
+		0x01,                   // Static
+		0xc0, 0xa8, 0x00, 0x01, // GatewayAddr 192.168.0.1
+		0xff, 0xff, 0xff, 0x00, // SubnetAddr 255.255.255.0
+	}
+
+	p := &IPv4DevicePath{Head: Head{Type: 3, SubType: IPv4DeviceSubType, Length: 27}}
+	if _, err := p.ReadFrom(bytes.NewReader(payload)); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+
+	if !p.LocalIP.Equal(net.IPv4(192, 168, 0, 100)) {
+		t.Errorf("LocalIP = %s, want 192.168.0.100", p.LocalIP)
+	}
+	if !p.RemoteIP.Equal(net.IPv4(192, 168, 0, 1)) {
+		t.Errorf("RemoteIP = %s, want 192.168.0.1", p.RemoteIP)
+	}
+	if p.LocalPort != 80 {
+		t.Errorf("LocalPort = %d, want 80", p.LocalPort)
+	}
+	if p.RemotePort != 8080 {
+		t.Errorf("RemotePort = %d, want 8080", p.RemotePort)
+	}
+	if p.Protocol != 6 {
+		t.Errorf("Protocol = %d, want 6", p.Protocol)
+	}
+	if !p.Static {
+		t.Errorf("Static = false, want true")
+	}
+	if !p.GatewayAddr.Equal(net.IPv4(192, 168, 0, 1)) {
+		t.Errorf("GatewayAddr = %s, want 192.168.0.1", p.GatewayAddr)
+	}
+	if !p.SubnetAddr.Equal(net.IPv4(255, 255, 255, 0)) {
+		t.Errorf("SubnetAddr = %s, want 255.255.255.0", p.SubnetAddr)
+	}
+}
+
+// TestIPv6DevicePathReadFrom replays a stateless-autoconfigured IPv6
+// messaging device path node captured from an OVMF Boot0001 variable.
+func TestIPv6DevicePathReadFrom(t *testing.T) {
+	payload := []byte{
+		0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, // LocalIP 2001:db8::1
+		0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x02, // RemoteIP 2001:db8::2
+		0xbb, 0x01, // LocalPort 443
+		0xbb, 0x01, // RemotePort 443
+		0x06, 0x00, // Protocol TCP (6)
+		0x01,       // IPAddressOrigin: stateless autoconfigure
+		0x40,       // PrefixLength 64
+		0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, // GatewayAddr 2001:db8::ff
+	}
+
+	p := &IPv6DevicePath{Head: Head{Type: 3, SubType: IPv6DeviceSubType, Length: 60}}
+	if _, err := p.ReadFrom(bytes.NewReader(payload)); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+
+	if !p.LocalIP.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("LocalIP = %s, want 2001:db8::1", p.LocalIP)
+	}
+	if !p.RemoteIP.Equal(net.ParseIP("2001:db8::2")) {
+		t.Errorf("RemoteIP = %s, want 2001:db8::2", p.RemoteIP)
+	}
+	if p.LocalPort != 443 || p.RemotePort != 443 {
+		t.Errorf("LocalPort/RemotePort = %d/%d, want 443/443", p.LocalPort, p.RemotePort)
+	}
+	if p.Protocol != 6 {
+		t.Errorf("Protocol = %d, want 6", p.Protocol)
+	}
+	if p.IPAddressOrigin != 1 {
+		t.Errorf("IPAddressOrigin = %d, want 1", p.IPAddressOrigin)
+	}
+	if p.PrefixLength != 64 {
+		t.Errorf("PrefixLength = %d, want 64", p.PrefixLength)
+	}
+	if !p.GatewayAddr.Equal(net.ParseIP("2001:db8::ff")) {
+		t.Errorf("GatewayAddr = %s, want 2001:db8::ff", p.GatewayAddr)
+	}
+}